@@ -0,0 +1,144 @@
+package bloomf
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+)
+
+// Binary format for MarshalBinary/UnmarshalBinary and LoadMmap: a fixed
+// 32-byte little-endian header followed by the raw bitvector bytes.  This is
+// far more compact than the gob encoding Dump/Load use, and lets a filter be
+// served straight out of a memory-mapped file without any decoding.  Prefer
+// this format for new code; Dump/Load are kept around for backwards
+// compatibility.
+const (
+	binaryMagic   = 0xB10F11 // identifies the format, distinct from other magics
+	binaryVersion = 1
+	headerSize    = 32 // magic(4) + version(4) + n(8) + count(8) + m(4) + k(4)
+)
+
+var (
+	errBufferTooShort = errors.New("bloomf: buffer too short for header")
+	errBadMagic       = errors.New("bloomf: bad magic, not a bloomf binary dump")
+	errBadVersion     = errors.New("bloomf: unsupported binary format version")
+	errMNotPow2       = errors.New("bloomf: m is not a power of two")
+	errLength         = errors.New("bloomf: buffer length inconsistent with header")
+	errBigEndianHost  = errors.New("bloomf: LoadMmap requires a little-endian host architecture")
+)
+
+// hostIsLittleEndian is determined once at startup; LoadMmap refuses to run
+// on a big-endian host rather than silently aliasing the bitvector with the
+// wrong byte order (MarshalBinary/UnmarshalBinary are unaffected, since they
+// always go through explicit binary.LittleEndian reads/writes).
+var hostIsLittleEndian = func() bool {
+	var x uint16 = 1
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// MarshalBinary encodes bf into the compact binary format: a fixed header
+// (magic, version, n, count, m, k) followed by the raw bitvector bytes.
+func (bf *BF) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, headerSize+len(bf.filter)*8)
+	putHeader(buf, bf.n, bf.count, bf.m, bf.k)
+	for i, w := range bf.filter {
+		binary.LittleEndian.PutUint64(buf[headerSize+i*8:], w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes data produced by MarshalBinary into bf.  The
+// hasher must be set separately, as it was with Load, since functions can't
+// be serialized.
+func (bf *BF) UnmarshalBinary(data []byte) error {
+	n, count, m, k, words, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+
+	filter := make(bitvector, words)
+	for i := range filter {
+		filter[i] = binary.LittleEndian.Uint64(data[headerSize+i*8:])
+	}
+
+	bf.n, bf.count, bf.m, bf.k, bf.filter = n, count, m, k, filter
+	return nil
+}
+
+// LoadMmap parses a buffer previously produced by MarshalBinary and returns
+// a BF whose bitvector directly aliases buf: no copy is made, so buf (for
+// example a memory-mapped file) must remain valid and must not be modified
+// for the lifetime of the returned filter. This only works on little-endian
+// architectures, since the aliased uint64s are read using the host's native
+// byte order thereafter (by get/set/getset, unlike Unmarshal's explicit
+// binary.LittleEndian reads); LoadMmap refuses to run at all on a
+// big-endian host rather than returning a filter with silently wrong bits.
+func LoadMmap(buf []byte, hasher func([]byte) uint64) (*BF, error) {
+	if !hostIsLittleEndian {
+		return nil, errBigEndianHost
+	}
+
+	n, count, m, k, words, err := parseHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	filterBytes := buf[headerSize : headerSize+words*8]
+	var filter bitvector
+	if words > 0 {
+		filter = unsafe.Slice((*uint64)(unsafe.Pointer(&filterBytes[0])), words)
+	}
+
+	return &BF{
+		n:      n,
+		count:  count,
+		m:      m,
+		k:      k,
+		filter: filter,
+		hash:   hasher,
+	}, nil
+}
+
+// putHeader writes the fixed header into buf, which must be at least headerSize bytes.
+func putHeader(buf []byte, n, count int, m, k uint32) {
+	binary.LittleEndian.PutUint32(buf[0:4], binaryMagic)
+	binary.LittleEndian.PutUint32(buf[4:8], binaryVersion)
+	binary.LittleEndian.PutUint64(buf[8:16], uint64(n))
+	binary.LittleEndian.PutUint64(buf[16:24], uint64(count))
+	binary.LittleEndian.PutUint32(buf[24:28], m)
+	binary.LittleEndian.PutUint32(buf[28:32], k)
+}
+
+// parseHeader validates and decodes the fixed header from data, and checks
+// that len(data) is consistent with the bitvector size implied by m.
+func parseHeader(data []byte) (n, count int, m, k uint32, words int, err error) {
+	if len(data) < headerSize {
+		err = errBufferTooShort
+		return
+	}
+	if binary.LittleEndian.Uint32(data[0:4]) != binaryMagic {
+		err = errBadMagic
+		return
+	}
+	if binary.LittleEndian.Uint32(data[4:8]) != binaryVersion {
+		err = errBadVersion
+		return
+	}
+
+	n = int(binary.LittleEndian.Uint64(data[8:16]))
+	count = int(binary.LittleEndian.Uint64(data[16:24]))
+	m = binary.LittleEndian.Uint32(data[24:28])
+	k = binary.LittleEndian.Uint32(data[28:32])
+
+	if m == 0 || m&(m-1) != 0 {
+		err = errMNotPow2
+		return
+	}
+
+	words = int((m + 63) / 64)
+	if len(data) != headerSize+words*8 {
+		err = errLength
+		return
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package bloomf
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestBinaryRoundTrip checks that MarshalBinary/UnmarshalBinary preserve
+// both the filter's bits and its fixed fields.
+func TestBinaryRoundTrip(t *testing.T) {
+	bf := New(1000, 0.01, fnv1a)
+
+	const n = 500
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+		bf.Insert(keys[i])
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	var got BF
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	got.hash = fnv1a
+
+	if got.n != bf.n || got.count != bf.count || got.m != bf.m || got.k != bf.k {
+		t.Fatalf("fields after round trip = %+v, want n=%d count=%d m=%d k=%d", got, bf.n, bf.count, bf.m, bf.k)
+	}
+	for _, key := range keys {
+		if !got.Lookup(key) {
+			t.Fatalf("lookup failed for inserted key %q after round trip", key)
+		}
+	}
+}
+
+// TestUnmarshalBinaryRejectsBadInput checks the header validation paths.
+func TestUnmarshalBinaryRejectsBadInput(t *testing.T) {
+	bf := New(1000, 0.01, fnv1a)
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	var short BF
+	if err := short.UnmarshalBinary(data[:headerSize-1]); err != errBufferTooShort {
+		t.Fatalf("short buffer: err = %v, want %v", err, errBufferTooShort)
+	}
+
+	var truncated BF
+	if err := truncated.UnmarshalBinary(data[:len(data)-8]); err != errLength {
+		t.Fatalf("truncated filter: err = %v, want %v", err, errLength)
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[0] ^= 0xff
+	var bad BF
+	if err := bad.UnmarshalBinary(corrupt); err != errBadMagic {
+		t.Fatalf("bad magic: err = %v, want %v", err, errBadMagic)
+	}
+}
+
+// TestLoadMmapAliasesBuffer checks that LoadMmap reads a valid filter back
+// and that it aliases the backing buffer rather than copying it: mutating
+// buf after the fact must be visible through the returned filter.
+func TestLoadMmapAliasesBuffer(t *testing.T) {
+	bf := New(1000, 0.01, fnv1a)
+	bf.Insert([]byte("hello"))
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	mm, err := LoadMmap(data, fnv1a)
+	if err != nil {
+		t.Fatalf("LoadMmap: %v", err)
+	}
+	if !mm.Lookup([]byte("hello")) {
+		t.Fatal("expected \"hello\" to be present right after LoadMmap")
+	}
+
+	for i := headerSize; i < len(data); i++ {
+		data[i] = 0
+	}
+
+	if mm.Lookup([]byte("hello")) {
+		t.Fatal("expected mutation of the backing buffer to be visible through the aliased filter")
+	}
+}
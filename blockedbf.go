@@ -0,0 +1,192 @@
+package bloomf
+
+import (
+	"encoding/gob"
+	"io"
+	"math/bits"
+)
+
+// blockBits is the size of a single block in bits, chosen to match a common
+// cache line size (512 bits = 64 bytes = 8 x uint64).
+const blockBits = 512
+
+// blockWords is the number of uint64 words per block.
+const blockWords = blockBits / 64
+
+// BlockedBF is a cache-efficient variant of BF.  Its bit vector is split into
+// fixed-size blocks sized to a cache line, and every key touches exactly one
+// block: the block index comes from the upper bits of the hash, and the k
+// bits within that block are set/tested with the same double-hashing scheme
+// BF uses.  This confines each Insert/Lookup to a single cache line, which is
+// a large throughput win once the filter no longer fits in L2/L3.
+type BlockedBF struct {
+	n         int       // capacity of the bloom filter
+	count     int       // number of elements which have been inserted
+	m         uint32    // size of bit vector in bits
+	k         uint32    // distinct hash functions needed, per block
+	numBlocks uint32    // number of blocks, always a power of two
+	filter    bitvector // our filter bit vector
+	hash      func([]byte) uint64
+}
+
+// NewBlocked returns a new blocked bloom filter with the specified capacity
+// and false positive rate.  Like New, the constructor takes the desired
+// capacity, false positive rate and hasher.
+func NewBlocked(capacity int, falsePositiveRate float64, hasher func([]byte) uint64) *BlockedBF {
+
+	m := FilterBits(capacity, falsePositiveRate)
+	if m < blockBits {
+		m = blockBits
+	}
+
+	numBlocks := m / blockBits
+
+	// k is picked with the same formula New uses, but scaled to the
+	// capacity of a single block rather than the whole filter, since
+	// every key only ever sets bits within one block.
+	capacityPerBlock := float64(capacity) / float64(numBlocks)
+	k := uint32(0.7 * float64(blockBits) / capacityPerBlock)
+	if k < 2 {
+		k = 2
+	}
+
+	return &BlockedBF{
+		m:         numBlocks * blockBits,
+		n:         capacity,
+		numBlocks: numBlocks,
+		filter:    newbv(numBlocks * blockBits),
+		hash:      hasher,
+		k:         k,
+	}
+}
+
+// Len is the number of items inserted into the filter
+func (bf *BlockedBF) Len() int { return bf.count }
+
+// Cap is the total capacity of the filter
+func (bf *BlockedBF) Cap() int { return bf.n }
+
+// block returns the index of the block that b hashes into, derived from the
+// topmost bits of h.  The in-block double-hash below only ever looks at the
+// low log2(blockBits) bits of h2 := uint32(h>>32), so the block index must
+// come from bits above that range; otherwise every key landing in the same
+// block would share the same double-hash stride and the filter's observed
+// false positive rate would blow past its target.
+func (bf *BlockedBF) block(h uint64) uint32 {
+	shift := 32 - bits.TrailingZeros32(bf.numBlocks)
+	return uint32(h>>32) >> uint(shift)
+}
+
+// Insert inserts the byte array b into the bloom filter.  Returns true if the
+// value was already considered to be in the bloom filter.  Increments the
+// count if it was not.
+func (bf *BlockedBF) Insert(b []byte) bool {
+	h := bf.hash(b)
+	base := bf.block(h) * blockBits
+	h1, h2 := uint32(h), uint32(h>>32)
+
+	var o uint = 1
+	for i := uint32(0); i < bf.k; i++ {
+		bit := base + ((h1 + (i * h2)) & (blockBits - 1))
+		o &= bf.filter.getset(bit)
+	}
+	bf.count += 1 - int(o)
+	return o == 1
+}
+
+// Lookup checks the bloom filter for the byte array b
+func (bf *BlockedBF) Lookup(b []byte) bool {
+	h := bf.hash(b)
+	base := bf.block(h) * blockBits
+	h1, h2 := uint32(h), uint32(h>>32)
+
+	for i := uint32(0); i < bf.k; i++ {
+		bit := base + ((h1 + (i * h2)) & (blockBits - 1))
+		if bf.filter.get(bit) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Merge adds bf2 into the current bloom filter.  They must have the same dimensions and use the same hash function.
+func (bf *BlockedBF) Merge(bf2 BlockedBF) {
+	// TODO(dgryski): verify parameters match
+	for i, v := range bf2.filter {
+		bf.filter[i] |= v
+	}
+}
+
+// Compress halves the number of blocks, at the cost of increased error rate.
+func (bf *BlockedBF) Compress() {
+
+	if bf.numBlocks&(bf.numBlocks-1) != 0 {
+		panic("numBlocks must be a power of two")
+	}
+
+	newNumBlocks := bf.numBlocks / 2
+
+	// We allocate a new array here so old space can actually be garbage collected.
+	// block() takes the block index from the top bits of the hash, so halving
+	// numBlocks maps old block b to b/2: fold each adjacent pair together.
+	row := make([]uint64, newNumBlocks*blockWords)
+	for blk := uint32(0); blk < newNumBlocks; blk++ {
+		for w := uint32(0); w < blockWords; w++ {
+			lo := (2*blk)*blockWords + w
+			hi := (2*blk+1)*blockWords + w
+			row[blk*blockWords+w] = bf.filter[lo] | bf.filter[hi]
+		}
+	}
+	bf.filter = row
+	bf.numBlocks = newNumBlocks
+	bf.m = newNumBlocks * blockBits
+}
+
+// Reset clears the bloom filter
+func (bf *BlockedBF) Reset() {
+	for i := range bf.filter {
+		bf.filter[i] = 0
+	}
+	bf.count = 0
+}
+
+// blockedState holds fixed fields of BlockedBF; should be kept in sync with BlockedBF
+type blockedState struct {
+	N      int       // capacity of the bloom filter
+	Count  int       // number of elements which have been inserted
+	M      uint32    // size of bit vector in bits
+	K      uint32    // distinct hash functions needed, per block
+	Filter bitvector // our filter bit vector
+}
+
+// Dump saves bloom filter state to w
+func (bf *BlockedBF) Dump(w io.Writer) error {
+	st := blockedState{
+		N:      bf.n,
+		Count:  bf.count,
+		M:      bf.m,
+		K:      bf.k,
+		Filter: bf.filter,
+	}
+	return gob.NewEncoder(w).Encode(st)
+}
+
+// LoadBlocked restores a blocked bloom filter from r. It is expected that it
+// was previously saved with Dump and hasher is the same that was used to
+// construct BlockedBF.
+func LoadBlocked(r io.Reader, hasher func([]byte) uint64) (*BlockedBF, error) {
+	var st blockedState
+	if err := gob.NewDecoder(r).Decode(&st); err != nil {
+		return nil, err
+	}
+	return &BlockedBF{
+		n:         st.N,
+		count:     st.Count,
+		m:         st.M,
+		k:         st.K,
+		numBlocks: st.M / blockBits,
+		filter:    st.Filter,
+		hash:      hasher,
+	}, nil
+}
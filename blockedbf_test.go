@@ -0,0 +1,57 @@
+package bloomf
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func fnv1a(b []byte) uint64 {
+	var h uint64 = 14695981039346656037
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// TestBlockedBFFalsePositiveRate checks that the block index and the
+// in-block double-hash don't share bits: if they did, every key in a block
+// would collide on stride and the observed false positive rate would blow
+// far past the target (see chunk0-1 review).
+func TestBlockedBFFalsePositiveRate(t *testing.T) {
+	const (
+		capacity = 1 << 20
+		target   = 0.01
+	)
+
+	bf := NewBlocked(capacity, target, fnv1a)
+
+	r := rand.New(rand.NewSource(1))
+	inserted := make(map[string]bool, capacity)
+	for i := 0; i < capacity; i++ {
+		key := []byte(fmt.Sprintf("key-%d", r.Int63()))
+		inserted[string(key)] = true
+		bf.Insert(key)
+	}
+
+	const trials = 100000
+	var falsePositives int
+	for i := 0; i < trials; i++ {
+		key := []byte(fmt.Sprintf("probe-%d", r.Int63()))
+		if inserted[string(key)] {
+			continue
+		}
+		if bf.Lookup(key) {
+			falsePositives++
+		}
+	}
+
+	fpr := float64(falsePositives) / float64(trials)
+	// Allow generous slack over target: this is an estimator, not an exact
+	// bound, but a correctly-mixed block index should stay within a small
+	// multiple of it. A bit-sharing bug inflated this past 18x in practice.
+	if fpr > target*5 {
+		t.Fatalf("observed false positive rate %.4f exceeds %.4f (5x target %.4f)", fpr, target*5, target)
+	}
+}
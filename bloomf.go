@@ -13,8 +13,11 @@ package bloomf
 
 import (
 	"encoding/gob"
+	"errors"
 	"io"
 	"math"
+	"math/bits"
+	"reflect"
 )
 
 // BF is a bloom filter
@@ -130,6 +133,86 @@ func (bf *BF) Reset() {
 	bf.count = 0
 }
 
+// ApproxCount estimates the number of distinct keys inserted into bf, based
+// on the observed bit fill ratio, using the Swamidass & Baldi estimator:
+// given X set bits out of m, n ≈ -(m/k) * ln(1 - X/m).
+func (bf *BF) ApproxCount() float64 {
+	x := float64(bf.filter.onesCount())
+	m := float64(bf.m)
+	k := float64(bf.k)
+	return -(m / k) * math.Log(1-x/m)
+}
+
+// errDimensionMismatch is returned by Jaccard and Union when the two
+// filters don't share the same m, k and hasher.
+var errDimensionMismatch = errors.New("bloomf: filters must have the same m, k and hasher")
+
+// sameDimensions reports whether a and b can be compared bit-for-bit.
+func sameDimensions(a, b *BF) bool {
+	return a.m == b.m && a.k == b.k &&
+		reflect.ValueOf(a.hash).Pointer() == reflect.ValueOf(b.hash).Pointer()
+}
+
+// Jaccard estimates the Jaccard similarity |A∩B| / |A∪B| between the sets of
+// keys inserted into a and b, from the popcounts of the AND and OR of their
+// bit vectors.  a and b must share the same m, k and hasher.
+func Jaccard(a, b *BF) (float64, error) {
+	if !sameDimensions(a, b) {
+		return 0, errDimensionMismatch
+	}
+
+	union := unionOnes(a, b)
+	if union == 0 {
+		return 0, nil
+	}
+
+	inter := estimateCount(intersectOnes(a, b), a.m, a.k)
+	return inter / estimateCount(union, a.m, a.k), nil
+}
+
+// Union estimates the number of distinct keys inserted across a and b
+// combined, |A∪B|, from the popcount of the OR of their bit vectors.  a and
+// b must share the same m, k and hasher.
+func Union(a, b *BF) (float64, error) {
+	if !sameDimensions(a, b) {
+		return 0, errDimensionMismatch
+	}
+	return estimateCount(unionOnes(a, b), a.m, a.k), nil
+}
+
+// estimateCount applies the Swamidass & Baldi estimator to a popcount of x
+// set bits out of m, for a filter with k hash functions.
+func estimateCount(x, m, k uint32) float64 {
+	return -(float64(m) / float64(k)) * math.Log(1-float64(x)/float64(m))
+}
+
+// intersectOnes returns the popcount of a.filter & b.filter.
+func intersectOnes(a, b *BF) uint32 {
+	var n uint32
+	for i, v := range a.filter {
+		n += uint32(bits.OnesCount64(v & b.filter[i]))
+	}
+	return n
+}
+
+// unionOnes returns the popcount of a.filter | b.filter.
+func unionOnes(a, b *BF) uint32 {
+	var n uint32
+	for i, v := range a.filter {
+		n += uint32(bits.OnesCount64(v | b.filter[i]))
+	}
+	return n
+}
+
+// onesCount returns the number of set bits in the bitvector.
+func (b bitvector) onesCount() uint32 {
+	var n uint32
+	for _, w := range b {
+		n += uint32(bits.OnesCount64(w))
+	}
+	return n
+}
+
 // state holds fixed fields of BF; should be kept in sync with BF
 type state struct {
 	N      int       // capacity of the bloom filter
@@ -139,7 +222,9 @@ type state struct {
 	Filter bitvector // our filter bit vector
 }
 
-// Dump saves bloom filter state to w
+// Dump saves bloom filter state to w using encoding/gob. Kept for backwards
+// compatibility; MarshalBinary produces a more compact encoding and should
+// be preferred in new code.
 func (bf *BF) Dump(w io.Writer) error {
 	st := state{
 		N:      bf.n,
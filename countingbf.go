@@ -0,0 +1,270 @@
+package bloomf
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// counterWidth4 and counterWidth8 are the supported counter widths, in bits,
+// for CountingBF.  4-bit counters are the default: they halve the memory
+// footprint of 8-bit counters while rarely saturating in practice.
+const (
+	counterWidth4 = 4
+	counterWidth8 = 8
+)
+
+// CountingBF is a counting variant of BF.  In place of a plain bitvector it
+// keeps a vector of small saturating counters, packed into uint64 words,
+// which lets Remove decrement the k slots associated with a key in addition
+// to the usual Insert and Lookup.
+//
+// Remove must only be called for keys that were previously passed to
+// Insert; calling it for a key that was never inserted will corrupt the
+// counters of whichever other keys happen to share those slots.
+type CountingBF struct {
+	n        int      // capacity of the bloom filter
+	count    int      // number of elements which have been inserted
+	m        uint32   // number of counters
+	k        uint32   // distinct hash functions needed
+	width    uint32   // bits per counter: counterWidth4 or counterWidth8
+	counters []uint64 // packed counters
+	hash     func([]byte) uint64
+}
+
+// NewCounting returns a new counting bloom filter with the specified
+// capacity and false positive rate, using 4-bit counters.
+func NewCounting(capacity int, falsePositiveRate float64, hasher func([]byte) uint64) *CountingBF {
+	return newCounting(capacity, falsePositiveRate, counterWidth4, hasher)
+}
+
+// NewCounting8 is like NewCounting but uses 8-bit counters, which tolerate a
+// higher multiplicity of any single slot before saturating, at twice the
+// memory cost.
+func NewCounting8(capacity int, falsePositiveRate float64, hasher func([]byte) uint64) *CountingBF {
+	return newCounting(capacity, falsePositiveRate, counterWidth8, hasher)
+}
+
+func newCounting(capacity int, falsePositiveRate float64, width uint32, hasher func([]byte) uint64) *CountingBF {
+	m := FilterBits(capacity, falsePositiveRate)
+
+	k := uint32(0.7 * float64(m) / float64(capacity))
+	if k < 2 {
+		k = 2
+	}
+
+	return &CountingBF{
+		m:        m,
+		n:        capacity,
+		k:        k,
+		width:    width,
+		counters: make([]uint64, countersWords(m, width)),
+		hash:     hasher,
+	}
+}
+
+// countersWords returns the number of uint64 words needed to hold n counters
+// of the given width.
+func countersWords(n, width uint32) uint32 {
+	perWord := 64 / width
+	return (n + perWord - 1) / perWord
+}
+
+// counterMax returns the saturation value for a counter of the given width.
+func counterMax(width uint32) uint64 {
+	return (uint64(1) << width) - 1
+}
+
+// get returns the value of counter i.
+func (bf *CountingBF) get(i uint32) uint64 {
+	perWord := 64 / bf.width
+	word := i / perWord
+	shift := (i % perWord) * bf.width
+	return (bf.counters[word] >> shift) & counterMax(bf.width)
+}
+
+// increment bumps counter i by one, saturating at the max value for bf.width.
+func (bf *CountingBF) increment(i uint32) {
+	perWord := 64 / bf.width
+	word := i / perWord
+	shift := (i % perWord) * bf.width
+	max := counterMax(bf.width)
+	if (bf.counters[word]>>shift)&max == max {
+		return
+	}
+	bf.counters[word] += uint64(1) << shift
+}
+
+// decrement drops counter i by one, with a floor of zero. A counter that has
+// saturated at counterMax is left alone: once pinned, the slot has lost the
+// true count of how many keys hash there, so a decrement could drive it to
+// zero (or some other too-small value) while keys that never overflowed it
+// are still relying on it, producing a false negative. Pinning it forever
+// is the safe choice the request calls for.
+func (bf *CountingBF) decrement(i uint32) {
+	perWord := 64 / bf.width
+	word := i / perWord
+	shift := (i % perWord) * bf.width
+	max := counterMax(bf.width)
+	cur := (bf.counters[word] >> shift) & max
+	if cur == 0 || cur == max {
+		return
+	}
+	bf.counters[word] -= uint64(1) << shift
+}
+
+// Len is the number of items inserted into the filter
+func (bf *CountingBF) Len() int { return bf.count }
+
+// Cap is the total capacity of the filter
+func (bf *CountingBF) Cap() int { return bf.n }
+
+// slots returns the k slot indices that b hashes to.
+func (bf *CountingBF) slots(b []byte) []uint32 {
+	h := bf.hash(b)
+	h1, h2 := uint32(h), uint32(h>>32)
+	idx := make([]uint32, bf.k)
+	for i := uint32(0); i < bf.k; i++ {
+		idx[i] = (h1 + (i * h2)) & (bf.m - 1)
+	}
+	return idx
+}
+
+// Insert inserts the byte array b into the bloom filter.  Returns true if
+// the value was already considered to be in the bloom filter.  Increments
+// the count if it was not.
+func (bf *CountingBF) Insert(b []byte) bool {
+	idx := bf.slots(b)
+
+	present := true
+	for _, i := range idx {
+		if bf.get(i) == 0 {
+			present = false
+			break
+		}
+	}
+
+	for _, i := range idx {
+		bf.increment(i)
+	}
+
+	if !present {
+		bf.count++
+	}
+	return present
+}
+
+// Lookup checks the bloom filter for the byte array b
+func (bf *CountingBF) Lookup(b []byte) bool {
+	for _, i := range bf.slots(b) {
+		if bf.get(i) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove decrements the k slots associated with b.  It must only be called
+// for a b that was previously passed to Insert.
+//
+// Len mirrors Insert's dedup semantics rather than tracking raw counter
+// operations: a duplicate Insert of an already-present key doesn't bump
+// Len, so a single matching Remove doesn't drop it either. Len is only
+// decremented once the slots actually fall back to zero, i.e. once Lookup
+// would start reporting false for b; until then b is still considered a
+// member of the set.
+func (bf *CountingBF) Remove(b []byte) {
+	idx := bf.slots(b)
+
+	wasPresent := true
+	for _, i := range idx {
+		if bf.get(i) == 0 {
+			wasPresent = false
+			break
+		}
+	}
+
+	for _, i := range idx {
+		bf.decrement(i)
+	}
+
+	if !wasPresent {
+		return
+	}
+
+	for _, i := range idx {
+		if bf.get(i) == 0 {
+			bf.count--
+			return
+		}
+	}
+}
+
+// ToBF projects the counting filter back into a plain BF, suitable for cheap
+// read-only distribution: any slot with a non-zero counter becomes a set bit.
+func (bf *CountingBF) ToBF() *BF {
+	out := &BF{
+		n:      bf.n,
+		count:  bf.count,
+		m:      bf.m,
+		k:      bf.k,
+		filter: newbv(bf.m),
+		hash:   bf.hash,
+	}
+	for i := uint32(0); i < bf.m; i++ {
+		if bf.get(i) != 0 {
+			out.filter.set(i)
+		}
+	}
+	return out
+}
+
+// Reset clears the bloom filter
+func (bf *CountingBF) Reset() {
+	for i := range bf.counters {
+		bf.counters[i] = 0
+	}
+	bf.count = 0
+}
+
+// countingState holds fixed fields of CountingBF; should be kept in sync
+// with CountingBF.
+type countingState struct {
+	N        int
+	Count    int
+	M        uint32
+	K        uint32
+	Width    uint32
+	Counters []uint64
+}
+
+// Dump saves bloom filter state to w
+func (bf *CountingBF) Dump(w io.Writer) error {
+	st := countingState{
+		N:        bf.n,
+		Count:    bf.count,
+		M:        bf.m,
+		K:        bf.k,
+		Width:    bf.width,
+		Counters: bf.counters,
+	}
+	return gob.NewEncoder(w).Encode(st)
+}
+
+// LoadCounting restores a counting bloom filter from r. It is expected that
+// it was previously saved with Dump and hasher is the same that was used to
+// construct CountingBF.
+func LoadCounting(r io.Reader, hasher func([]byte) uint64) (*CountingBF, error) {
+	var st countingState
+	if err := gob.NewDecoder(r).Decode(&st); err != nil {
+		return nil, err
+	}
+	return &CountingBF{
+		n:        st.N,
+		count:    st.Count,
+		m:        st.M,
+		k:        st.K,
+		width:    st.Width,
+		counters: st.Counters,
+		hash:     hasher,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package bloomf
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCountingBFDecrementPinned checks that decrement never moves a
+// saturated counter, mirroring increment's saturation guard.
+func TestCountingBFDecrementPinned(t *testing.T) {
+	cbf := NewCounting(10, 0.1, fnv1a)
+	max := counterMax(cbf.width)
+
+	var slot uint32
+	for i := uint64(0); i < max+5; i++ {
+		cbf.increment(slot)
+	}
+	if got := cbf.get(slot); got != max {
+		t.Fatalf("counter = %d, want saturated at %d", got, max)
+	}
+
+	for i := uint64(0); i < max; i++ {
+		cbf.decrement(slot)
+	}
+	if got := cbf.get(slot); got != max {
+		t.Fatalf("saturated counter moved after decrement: got %d, want pinned at %d", got, max)
+	}
+}
+
+// TestCountingBFSaturationNoFalseNegative reproduces the scenario from the
+// chunk0-3 review: more distinct keys collide on one slot than the counter
+// can represent, so the slot saturates and loses the true count. Removing
+// only as many keys as the counter could hold must not drain the slot to
+// zero, since keys beyond the saturation point are still present.
+func TestCountingBFSaturationNoFalseNegative(t *testing.T) {
+	constHash := func(b []byte) uint64 { return 42 }
+
+	cbf := NewCounting(4, 0.5, constHash)
+	max := counterMax(cbf.width)
+
+	n := int(max) + 5
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+		cbf.Insert(keys[i])
+	}
+
+	for _, i := range cbf.slots(keys[0]) {
+		if got := cbf.get(i); got != max {
+			t.Fatalf("slot %d = %d, want saturated at %d", i, got, max)
+		}
+	}
+
+	for i := 0; i < int(max); i++ {
+		cbf.Remove(keys[i])
+	}
+
+	for i := int(max); i < n; i++ {
+		if !cbf.Lookup(keys[i]) {
+			t.Fatalf("false negative for still-present key %q after saturated slot was drained", keys[i])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package bloomf
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestApproxCount checks that ApproxCount tracks the number of distinct
+// keys actually inserted, within a generous tolerance for the estimator.
+func TestApproxCount(t *testing.T) {
+	const n = 5000
+	bf := New(n, 0.01, fnv1a)
+	for i := 0; i < n; i++ {
+		bf.Insert([]byte(fmt.Sprintf("key-%d", i)))
+	}
+
+	got := bf.ApproxCount()
+	if diff := math.Abs(got-n) / n; diff > 0.05 {
+		t.Fatalf("ApproxCount() = %.1f, want within 5%% of %d", got, n)
+	}
+}
+
+// TestJaccardAndUnion checks the estimators against known overlapping and
+// disjoint key sets, and that dimension mismatches are rejected.
+func TestJaccardAndUnion(t *testing.T) {
+	const n = 2000
+	a := New(n, 0.01, fnv1a)
+	b := New(n, 0.01, fnv1a)
+
+	for i := 0; i < 1000; i++ {
+		a.Insert([]byte(fmt.Sprintf("shared-%d", i)))
+		b.Insert([]byte(fmt.Sprintf("shared-%d", i)))
+	}
+	for i := 0; i < 500; i++ {
+		a.Insert([]byte(fmt.Sprintf("a-only-%d", i)))
+	}
+	for i := 0; i < 500; i++ {
+		b.Insert([]byte(fmt.Sprintf("b-only-%d", i)))
+	}
+
+	// |A∩B| ≈ 1000, |A∪B| ≈ 2000, so Jaccard ≈ 0.5.
+	j, err := Jaccard(a, b)
+	if err != nil {
+		t.Fatalf("Jaccard: %v", err)
+	}
+	if math.Abs(j-0.5) > 0.1 {
+		t.Fatalf("Jaccard(a, b) = %.3f, want close to 0.5", j)
+	}
+
+	u, err := Union(a, b)
+	if err != nil {
+		t.Fatalf("Union: %v", err)
+	}
+	if math.Abs(u-2000) > 200 {
+		t.Fatalf("Union(a, b) = %.1f, want close to 2000", u)
+	}
+
+	other := New(n, 0.05, fnv1a)
+	if _, err := Jaccard(a, other); err != errDimensionMismatch {
+		t.Fatalf("Jaccard with mismatched filters: err = %v, want %v", err, errDimensionMismatch)
+	}
+	if _, err := Union(a, other); err != errDimensionMismatch {
+		t.Fatalf("Union with mismatched filters: err = %v, want %v", err, errDimensionMismatch)
+	}
+}
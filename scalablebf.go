@@ -0,0 +1,164 @@
+package bloomf
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// defaultGrowthFactor and defaultTighteningRatio are the s and r parameters
+// from the Almeida/Baquero scalable bloom filter scheme, used when callers
+// don't specify their own via NewScalableBFWith.
+const (
+	defaultGrowthFactor    = 2
+	defaultTighteningRatio = 0.9
+)
+
+// ScalableBF is a bloom filter that grows to accommodate more elements than
+// its initial capacity, following the scheme described by Almeida and
+// Baquero in "Scalable Bloom Filters".  It keeps a slice of BF filters:
+// whenever the newest one fills up, a new filter is appended with capacity
+// scaled by a growth factor s and a false positive rate tightened by a ratio
+// r, so the compounded false positive rate across all filters stays bounded
+// by P0/(1-r).
+type ScalableBF struct {
+	s       float64 // growth factor applied to capacity on each new filter
+	r       float64 // tightening ratio applied to false positive rate on each new filter
+	fpr     float64 // false positive rate that will be used for the next filter
+	filters []*BF
+	hash    func([]byte) uint64
+}
+
+// NewScalableBF returns a new scalable bloom filter seeded with an initial
+// filter of the given capacity and false positive rate, using the default
+// growth factor and tightening ratio.
+func NewScalableBF(capacity int, falsePositiveRate float64, hasher func([]byte) uint64) *ScalableBF {
+	return NewScalableBFWith(capacity, falsePositiveRate, defaultGrowthFactor, defaultTighteningRatio, hasher)
+}
+
+// NewScalableBFWith is like NewScalableBF but lets the caller configure the
+// growth factor s and the tightening ratio r.
+func NewScalableBFWith(capacity int, falsePositiveRate, s, r float64, hasher func([]byte) uint64) *ScalableBF {
+	return &ScalableBF{
+		s:       s,
+		r:       r,
+		fpr:     falsePositiveRate * r,
+		filters: []*BF{New(capacity, falsePositiveRate, hasher)},
+		hash:    hasher,
+	}
+}
+
+// Len is the number of items inserted into the filter
+func (sbf *ScalableBF) Len() int {
+	var n int
+	for _, f := range sbf.filters {
+		n += f.Len()
+	}
+	return n
+}
+
+// Cap is the total capacity of the filter across all sub-filters
+func (sbf *ScalableBF) Cap() int {
+	var n int
+	for _, f := range sbf.filters {
+		n += f.Cap()
+	}
+	return n
+}
+
+// Insert inserts the byte array b into the bloom filter.  Returns true if
+// the value was already considered to be in the bloom filter.  Older
+// filters are checked first so a key already present isn't counted again;
+// new keys are inserted only into the newest filter, which is grown once it
+// reaches its target capacity.
+func (sbf *ScalableBF) Insert(b []byte) bool {
+	for _, f := range sbf.filters {
+		if f.Lookup(b) {
+			return true
+		}
+	}
+
+	cur := sbf.filters[len(sbf.filters)-1]
+	cur.Insert(b)
+	if cur.Len() >= cur.Cap() {
+		sbf.grow()
+	}
+	return false
+}
+
+// grow appends a new, larger, tighter-FPR filter to sbf.
+func (sbf *ScalableBF) grow() {
+	last := sbf.filters[len(sbf.filters)-1]
+	newCap := int(sbf.s * float64(last.Cap()))
+	sbf.filters = append(sbf.filters, New(newCap, sbf.fpr, sbf.hash))
+	sbf.fpr *= sbf.r
+}
+
+// Lookup checks the bloom filter for the byte array b.  It returns true if
+// any of the sub-filters report a match.
+func (sbf *ScalableBF) Lookup(b []byte) bool {
+	for _, f := range sbf.filters {
+		if f.Lookup(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// scalableState holds the serializable fields of ScalableBF; should be kept
+// in sync with ScalableBF.
+type scalableState struct {
+	S       float64
+	R       float64
+	FPR     float64
+	Filters []state
+}
+
+// Dump saves the scalable bloom filter state, including every sub-filter, to w.
+func (sbf *ScalableBF) Dump(w io.Writer) error {
+	st := scalableState{
+		S:       sbf.s,
+		R:       sbf.r,
+		FPR:     sbf.fpr,
+		Filters: make([]state, len(sbf.filters)),
+	}
+	for i, f := range sbf.filters {
+		st.Filters[i] = state{
+			N:      f.n,
+			Count:  f.count,
+			M:      f.m,
+			K:      f.k,
+			Filter: f.filter,
+		}
+	}
+	return gob.NewEncoder(w).Encode(st)
+}
+
+// LoadScalableBF restores a scalable bloom filter from r. It is expected
+// that it was previously saved with Dump and hasher is the same that was
+// used to construct ScalableBF.
+func LoadScalableBF(r io.Reader, hasher func([]byte) uint64) (*ScalableBF, error) {
+	var st scalableState
+	if err := gob.NewDecoder(r).Decode(&st); err != nil {
+		return nil, err
+	}
+
+	filters := make([]*BF, len(st.Filters))
+	for i, fs := range st.Filters {
+		filters[i] = &BF{
+			n:      fs.N,
+			count:  fs.Count,
+			m:      fs.M,
+			k:      fs.K,
+			filter: fs.Filter,
+			hash:   hasher,
+		}
+	}
+
+	return &ScalableBF{
+		s:       st.S,
+		r:       st.R,
+		fpr:     st.FPR,
+		filters: filters,
+		hash:    hasher,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package bloomf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestScalableBFGrow checks that a ScalableBF grows past its initial
+// capacity, keeps every inserted key retrievable, and reports Len/Cap
+// consistent with the sub-filters it grew into.
+func TestScalableBFGrow(t *testing.T) {
+	sbf := NewScalableBFWith(10, 0.01, 2, 0.9, fnv1a)
+
+	const n = 1000
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+		sbf.Insert(keys[i])
+	}
+
+	if len(sbf.filters) <= 1 {
+		t.Fatalf("filters = %d, want more than 1 after inserting well past the initial capacity", len(sbf.filters))
+	}
+
+	for _, key := range keys {
+		if !sbf.Lookup(key) {
+			t.Fatalf("lookup failed for inserted key %q", key)
+		}
+	}
+
+	if got := sbf.Len(); got < n-n/10 {
+		// a small shortfall is expected from false-positive dedup on Insert
+		t.Fatalf("Len() = %d, want close to %d", got, n)
+	}
+	if got := sbf.Cap(); got < n {
+		t.Fatalf("Cap() = %d, want at least %d after growth", got, n)
+	}
+}
+
+// TestScalableBFDumpLoad checks that Dump/Load round-trips every sub-filter.
+func TestScalableBFDumpLoad(t *testing.T) {
+	sbf := NewScalableBFWith(10, 0.1, 2, 0.9, fnv1a)
+
+	const n = 200
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+		sbf.Insert(keys[i])
+	}
+
+	var buf bytes.Buffer
+	if err := sbf.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+
+	loaded, err := LoadScalableBF(&buf, fnv1a)
+	if err != nil {
+		t.Fatalf("LoadScalableBF: %v", err)
+	}
+
+	if len(loaded.filters) != len(sbf.filters) {
+		t.Fatalf("filters = %d, want %d", len(loaded.filters), len(sbf.filters))
+	}
+	for _, key := range keys {
+		if !loaded.Lookup(key) {
+			t.Fatalf("lookup failed for inserted key %q after Dump/Load", key)
+		}
+	}
+	if loaded.Len() != sbf.Len() {
+		t.Fatalf("Len() = %d after Dump/Load, want %d", loaded.Len(), sbf.Len())
+	}
+}